@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand"
 
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/gocty"
@@ -40,6 +41,12 @@ type ConfigModuleCall struct {
 	// the child module has declared as optional and which the call will
 	// just leave to take on their default values.
 	Arguments map[addrs.InputVariable]ConfigExpr
+
+	// DependsOn, if non-empty, is a set of references to other objects
+	// visible in the calling module that this call should declare an
+	// explicit "depends_on" dependency on, independently of any data-flow
+	// dependency its Arguments or repetition expressions might carry.
+	DependsOn []ConfigExpr
 }
 
 var _ ConfigObject = (*ConfigModuleCall)(nil)
@@ -102,12 +109,103 @@ func (o *ConfigModuleCall) AppendConfig(to *hclwrite.Body) {
 		body.SetAttributeRaw(addr.Name, expr.BuildExpr().BuildTokens(nil))
 	}
 
+	if len(o.DependsOn) > 0 {
+		body.AppendNewline()
+		body.SetAttributeRaw("depends_on", buildExprListTokens(o.DependsOn))
+	}
+
 	to.AppendBlock(block)
 }
 
 // GenerateModified implements ConfigObject.GenerateModified.
+//
+// It produces a new ConfigModuleCall that differs from the receiver in
+// at most one of: its repetition mode, the set of arguments it explicitly
+// sets, or one of its child objects. The result can equal the receiver when
+// the chosen mutation has nothing to act on. ns is expected to be the same
+// namespace the receiver was originally generated against, and is used
+// both to generate any new expressions the mutation needs and to declare
+// in it the referenceables of the mutated call.
 func (o *ConfigModuleCall) GenerateModified(rnd *rand.Rand, ns *Namespace) ConfigObject {
-	return o
+	profile := profileFor(ns)
+	mutated := shallowCopyModuleCall(o)
+	mutated.Arguments = copyArguments(o.Arguments)
+	mutated.Objects = append([]ConfigObject(nil), o.Objects...)
+
+	const (
+		mutateRepetition int = 0
+		mutateArguments  int = 1
+		mutateChildObj   int = 2
+	)
+	switch decideIndex(rnd, []int{
+		mutateRepetition: 1,
+		mutateArguments:  1,
+		mutateChildObj:   2,
+	}) {
+	case mutateRepetition:
+		mutated.ForEachExpr, mutated.CountExpr = generateRepetition(rnd, ns, profile)
+
+	case mutateArguments:
+		mutateModuleCallArguments(rnd, ns, mutated)
+
+	case mutateChildObj:
+		// Index 0 is always the mandatory ConfigBoilerplate object, which
+		// isn't meaningful to regenerate, so we only ever swap out the
+		// rest.
+		if len(mutated.Objects) > 1 {
+			i := 1 + rnd.Intn(len(mutated.Objects)-1)
+			// old may have ended up in mutated.Arguments either because it
+			// was CallerWillSet or because it has a default and generation,
+			// per profile.ArgumentSetProbability, chose to set it anyway.
+			// Either way, once old is gone from
+			// Objects its entry in Arguments is stale and must go too.
+			if old, ok := mutated.Objects[i].(*ConfigVariable); ok {
+				delete(mutated.Arguments, old.Addr)
+			}
+			newObj := GenerateConfigObject(rnd, mutated.ChildNamespace)
+			mutated.Objects[i] = newObj
+			if cv, ok := newObj.(*ConfigVariable); ok && (cv.CallerWillSet || rnd.Float64() < profile.ArgumentSetProbability) {
+				// The expression comes from ns here because the arguments
+				// are defined in the calling module, not the called module.
+				mutated.Arguments[cv.Addr] = ns.GenerateExpression(rnd)
+			}
+		}
+	}
+
+	// Whatever we changed above may have altered the set of instances or
+	// outputs this call makes referenceable, so we declare the mutated
+	// call in ns. This only adds declarations: any that ns already holds
+	// for instance keys or outputs the mutation removed stay in place.
+	declareConfigModuleCall(mutated, ns)
+	return mutated
+}
+
+// mutateModuleCallArguments either adds an argument for a declared,
+// not-yet-set CallerWillSet variable, or removes one of the arguments
+// already present, each with equal likelihood.
+func mutateModuleCallArguments(rnd *rand.Rand, ns *Namespace, mc *ConfigModuleCall) {
+	var unset []addrs.InputVariable
+	for _, obj := range mc.Objects {
+		cv, ok := obj.(*ConfigVariable)
+		if !ok || !cv.CallerWillSet {
+			continue
+		}
+		if _, ok := mc.Arguments[cv.Addr]; !ok {
+			unset = append(unset, cv.Addr)
+		}
+	}
+
+	switch {
+	case len(unset) > 0 && (len(mc.Arguments) == 0 || rnd.Intn(2) == 0):
+		addr := unset[rnd.Intn(len(unset))]
+		mc.Arguments[addr] = ns.GenerateExpression(rnd)
+	case len(mc.Arguments) > 0:
+		set := make([]addrs.InputVariable, 0, len(mc.Arguments))
+		for addr := range mc.Arguments {
+			set = append(set, addr)
+		}
+		delete(mc.Arguments, set[rnd.Intn(len(set))])
+	}
 }
 
 // Instantiate implements ConfigObject.Instantiate.
@@ -224,3 +322,19 @@ func (o *ConfigModuleCallInstance) CheckState(prior, new *states.State) []error
 
 	return nil
 }
+
+// buildExprListTokens renders exprs as an HCL tuple expression, suitable
+// for attributes like "depends_on" that take a list of references.
+func buildExprListTokens(exprs []ConfigExpr) hclwrite.Tokens {
+	toks := hclwrite.Tokens{
+		&hclwrite.Token{Type: hclsyntax.TokenOBrack, Bytes: []byte{'['}},
+	}
+	for i, expr := range exprs {
+		if i > 0 {
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte{','}})
+		}
+		toks = append(toks, expr.BuildExpr().BuildTokens(nil)...)
+	}
+	toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte{']'}})
+	return toks
+}
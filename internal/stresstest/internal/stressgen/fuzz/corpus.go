@@ -0,0 +1,111 @@
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// entry is the on-disk representation of a single kept seed.
+type entry struct {
+	Seed       int64  `json:"seed"`
+	ParentSeed *int64 `json:"parent_seed,omitempty"`
+	Signal     Signal `json:"signal"`
+}
+
+// Corpus is a directory of kept seeds, each of which reached a distinct
+// coverage Signal. It's safe to reopen a Corpus from a previous run: the
+// set of buckets already covered, and the seeds needed to reproduce them,
+// are reloaded from disk.
+type Corpus struct {
+	dir     string
+	buckets map[string]bool
+	seeds   []int64
+	parents map[int64]*int64
+}
+
+// OpenCorpus loads the corpus persisted at dir, creating the directory if
+// it doesn't already exist.
+func OpenCorpus(dir string) (*Corpus, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating corpus directory: %w", err)
+	}
+
+	c := &Corpus{
+		dir:     dir,
+		buckets: make(map[string]bool),
+		parents: make(map[int64]*int64),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading corpus directory: %w", err)
+	}
+	for _, de := range entries {
+		if de.IsDir() || filepath.Ext(de.Name()) != ".json" {
+			continue
+		}
+		e, err := readEntry(filepath.Join(dir, de.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading corpus entry %s: %w", de.Name(), err)
+		}
+		c.buckets[e.Signal.Bucket()] = true
+		c.seeds = append(c.seeds, e.Seed)
+		c.parents[e.Seed] = e.ParentSeed
+	}
+
+	return c, nil
+}
+
+// Parent returns the seed that the given kept seed was mutated from, or
+// nil if it was generated fresh.
+func (c *Corpus) Parent(seed int64) *int64 {
+	return c.parents[seed]
+}
+
+// Seeds returns the seeds of all configurations currently kept in the
+// corpus, suitable for picking one at random to mutate.
+func (c *Corpus) Seeds() []int64 {
+	return c.seeds
+}
+
+// Covers reports whether sig's bucket is already represented by a seed in
+// the corpus.
+func (c *Corpus) Covers(sig Signal) bool {
+	return c.buckets[sig.Bucket()]
+}
+
+// Add records seed as reproducing sig, optionally as a mutation of
+// parentSeed, persisting it to disk. It's the caller's responsibility to
+// have already checked Covers and decided the signal is worth keeping;
+// Add itself doesn't deduplicate.
+func (c *Corpus) Add(seed int64, parentSeed *int64, sig Signal) error {
+	e := entry{Seed: seed, ParentSeed: parentSeed, Signal: sig}
+	path := filepath.Join(c.dir, fmt.Sprintf("%d.json", seed))
+
+	raw, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding corpus entry: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("writing corpus entry: %w", err)
+	}
+
+	c.buckets[sig.Bucket()] = true
+	c.seeds = append(c.seeds, seed)
+	c.parents[seed] = parentSeed
+	return nil
+}
+
+func readEntry(path string) (entry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return entry{}, err
+	}
+	var e entry
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return entry{}, err
+	}
+	return e, nil
+}
@@ -0,0 +1,168 @@
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/hashicorp/terraform/internal/stresstest/internal/stressgen"
+	"github.com/hashicorp/terraform/states"
+)
+
+// GenerateFunc produces a fresh root Config using rnd, biased by profile.
+// Callers supply this rather than the fuzz package hardcoding an
+// entry point, since only the caller knows how its root Namespace is
+// constructed.
+type GenerateFunc func(rnd *rand.Rand, profile *stressgen.GenerationProfile) *stressgen.Config
+
+// ApplyFunc instantiates cfg's configuration against Terraform Core and
+// returns the prior and new states.State that resulted, mirroring the
+// arguments ConfigObjectInstance.CheckState expects.
+type ApplyFunc func(cfg *stressgen.Config) (prior, new *states.State, err error)
+
+// RunOptions configures a single call to Run.
+type RunOptions struct {
+	Profile    *stressgen.GenerationProfile
+	Corpus     *Corpus
+	Generate   GenerateFunc
+	Apply      ApplyFunc
+	Iterations int
+
+	// Rand drives which seeds are chosen and whether an iteration
+	// generates fresh or mutates a corpus survivor. It does not need to
+	// be the same source used by Generate or by the per-seed rand.Rand
+	// instances Run constructs internally.
+	Rand *rand.Rand
+}
+
+// Run drives Iterations rounds of the coverage-guided loop: each round
+// either generates a fresh configuration or mutates a seed already kept in
+// the corpus, applies it via Apply, computes its Signal, and keeps it in
+// the corpus if that Signal hasn't been seen before.
+func Run(opts RunOptions) error {
+	rnd := opts.Rand
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(1))
+	}
+
+	for i := 0; i < opts.Iterations; i++ {
+		seed, parentSeed, cfg, err := nextCandidate(rnd, opts)
+		if err != nil {
+			return fmt.Errorf("building candidate: %w", err)
+		}
+
+		_, new, err := opts.Apply(cfg)
+		if err != nil {
+			return fmt.Errorf("applying seed %d: %w", seed, err)
+		}
+
+		sig := ExtractSignal(cfg, new)
+		if opts.Corpus.Covers(sig) {
+			continue
+		}
+		if err := opts.Corpus.Add(seed, parentSeed, sig); err != nil {
+			return fmt.Errorf("keeping seed %d: %w", seed, err)
+		}
+	}
+
+	return nil
+}
+
+// nextCandidate picks the next configuration to try: with even odds, and
+// only when the corpus already has survivors, it mutates an existing one;
+// otherwise it generates a brand new configuration. Either way the result
+// is reproducible from the returned seed (and, for a mutation, its parent
+// seed) alone.
+func nextCandidate(rnd *rand.Rand, opts RunOptions) (seed int64, parentSeed *int64, cfg *stressgen.Config, err error) {
+	seeds := opts.Corpus.Seeds()
+	if len(seeds) > 0 && rnd.Intn(2) == 0 {
+		base := seeds[rnd.Intn(len(seeds))]
+		baseCfg, err := regenerate(opts, base)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		mutateSeed := rnd.Int63()
+		mutated := mutateConfig(rand.New(rand.NewSource(mutateSeed)), baseCfg)
+		return mutateSeed, &base, mutated, nil
+	}
+
+	genSeed := rnd.Int63()
+	cfg = opts.Generate(rand.New(rand.NewSource(genSeed)), opts.Profile)
+	return genSeed, nil, cfg, nil
+}
+
+// regenerate rebuilds the Config that a kept seed represents, replaying
+// its full chain of mutations from its original generated ancestor. This
+// is what makes the corpus resumable across process restarts: only the
+// seeds, not the configurations themselves, need to be persisted.
+func regenerate(opts RunOptions, seed int64) (*stressgen.Config, error) {
+	parent := opts.Corpus.Parent(seed)
+	if parent == nil {
+		return opts.Generate(rand.New(rand.NewSource(seed)), opts.Profile), nil
+	}
+	base, err := regenerate(opts, *parent)
+	if err != nil {
+		return nil, err
+	}
+	return mutateConfig(rand.New(rand.NewSource(seed)), base), nil
+}
+
+// mutateConfig picks a uniformly random ConfigObject anywhere in cfg's
+// tree and replaces it with the result of its GenerateModified, leaving
+// the rest of the tree untouched.
+func mutateConfig(rnd *rand.Rand, cfg *stressgen.Config) *stressgen.Config {
+	total := countObjects(cfg.RootObjects)
+	if total == 0 {
+		return cfg
+	}
+	objs, ok := replaceNth(rnd, cfg.RootObjects, cfg.RootNS, rnd.Intn(total))
+	if !ok {
+		return cfg
+	}
+	return &stressgen.Config{RootNS: cfg.RootNS, RootObjects: objs}
+}
+
+// countObjects counts every ConfigObject reachable from objs, including
+// objs itself and, recursively, the children of any ConfigModuleCall.
+func countObjects(objs []stressgen.ConfigObject) int {
+	n := len(objs)
+	for _, obj := range objs {
+		if mc, ok := obj.(*stressgen.ConfigModuleCall); ok {
+			n += countObjects(mc.Objects)
+		}
+	}
+	return n
+}
+
+// replaceNth replaces the n-th object in a depth-first walk of objs (where
+// objs itself is visited before descending into any ConfigModuleCall's
+// children) with its GenerateModified result, returning the new slice.
+func replaceNth(rnd *rand.Rand, objs []stressgen.ConfigObject, ns *stressgen.Namespace, n int) ([]stressgen.ConfigObject, bool) {
+	if n < len(objs) {
+		out := append([]stressgen.ConfigObject(nil), objs...)
+		out[n] = objs[n].GenerateModified(rnd, ns)
+		return out, true
+	}
+	n -= len(objs)
+
+	for i, obj := range objs {
+		mc, ok := obj.(*stressgen.ConfigModuleCall)
+		if !ok {
+			continue
+		}
+		sub := countObjects(mc.Objects)
+		if n < sub {
+			childObjs, ok := replaceNth(rnd, mc.Objects, mc.ChildNamespace, n)
+			if !ok {
+				return objs, false
+			}
+			mcCopy := *mc
+			mcCopy.Objects = childObjs
+			out := append([]stressgen.ConfigObject(nil), objs...)
+			out[i] = &mcCopy
+			return out, true
+		}
+		n -= sub
+	}
+
+	return objs, false
+}
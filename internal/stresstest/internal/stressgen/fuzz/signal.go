@@ -0,0 +1,198 @@
+// Package fuzz turns stressgen's one-shot random generation into a
+// coverage-guided loop: it tracks a coverage signal for each generated
+// configuration, keeps only the seeds that reach a previously-unseen
+// signal, and mutates those survivors to search for further novelty.
+package fuzz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/addrs"
+	"github.com/hashicorp/terraform/internal/stresstest/internal/stressgen"
+	"github.com/hashicorp/terraform/states"
+)
+
+// Signal is a coarse summary of how "interesting" a generated
+// configuration's shape and resulting state were. Two configurations that
+// produce an equal Signal are considered to exercise the same corner of
+// Terraform Core, so the corpus keeps at most one seed per distinct
+// Signal.
+type Signal struct {
+	// MaxModuleDepth is how many levels of nested module calls the
+	// configuration reached.
+	MaxModuleDepth int
+
+	// RepetitionModesByDepth records, for each module depth, which of
+	// "no-key" (single-instance), "for_each", and "count" were used by at
+	// least one module call at that depth.
+	RepetitionModesByDepth map[int]string
+
+	// ResourceTypes is the sorted, deduplicated set of resource types
+	// that appeared anywhere in the resulting state.
+	ResourceTypes []string
+
+	// HasModuleToModuleRef is true if the configuration contains at least
+	// one reference -- an argument, a depends_on entry, or a repetition
+	// expression -- from one module call to another module call's
+	// output.
+	HasModuleToModuleRef bool
+}
+
+// Bucket returns a string that's equal for two Signals if and only if they
+// represent the same coverage bucket. It's used as the corpus's dedup key;
+// a kept seed's metadata file on disk is named after the seed, not the
+// bucket.
+func (s Signal) Bucket() string {
+	var depths []int
+	for d := range s.RepetitionModesByDepth {
+		depths = append(depths, d)
+	}
+	sort.Ints(depths)
+
+	var modes strings.Builder
+	for _, d := range depths {
+		fmt.Fprintf(&modes, "%d:%s,", d, s.RepetitionModesByDepth[d])
+	}
+
+	return fmt.Sprintf(
+		"depth=%d/modes=%s/types=%s/xref=%v",
+		s.MaxModuleDepth,
+		modes.String(),
+		strings.Join(s.ResourceTypes, ","),
+		s.HasModuleToModuleRef,
+	)
+}
+
+// ExtractSignal computes the Signal for a configuration that was just
+// instantiated and applied, given the resulting state.
+func ExtractSignal(cfg *stressgen.Config, state *states.State) Signal {
+	modesByDepth := make(map[int]string)
+	maxDepth := 0
+	hasXRef := false
+	walkModuleCalls(cfg.RootObjects, 1, func(depth int, mc *stressgen.ConfigModuleCall) {
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		modesByDepth[depth] += repetitionMode(mc)
+		if moduleCallHasCrossModuleRef(mc) {
+			hasXRef = true
+		}
+	})
+	for d, modes := range modesByDepth {
+		modesByDepth[d] = dedupChars(modes)
+	}
+
+	return Signal{
+		MaxModuleDepth:         maxDepth,
+		RepetitionModesByDepth: modesByDepth,
+		ResourceTypes:          resourceTypeMix(state),
+		HasModuleToModuleRef:   hasXRef,
+	}
+}
+
+// walkModuleCalls calls visit for every ConfigModuleCall reachable from
+// objs, at any depth, passing the 1-based depth at which it was found.
+func walkModuleCalls(objs []stressgen.ConfigObject, depth int, visit func(int, *stressgen.ConfigModuleCall)) {
+	for _, obj := range objs {
+		mc, ok := obj.(*stressgen.ConfigModuleCall)
+		if !ok {
+			continue
+		}
+		visit(depth, mc)
+		walkModuleCalls(mc.Objects, depth+1, visit)
+	}
+}
+
+// repetitionMode returns a single character identifying which repetition
+// mode a module call is using: "s" for single-instance, "e" for
+// "for_each", or "c" for "count".
+func repetitionMode(mc *stressgen.ConfigModuleCall) string {
+	switch {
+	case mc.ForEachExpr != nil:
+		return "e"
+	case mc.CountExpr != nil:
+		return "c"
+	default:
+		return "s"
+	}
+}
+
+// moduleCallHasCrossModuleRef reports whether any of mc's arguments,
+// depends_on entries, or repetition expressions ("for_each"/"count")
+// reference another module call's output specifically, as opposed to a
+// resource, variable, or local in the same module -- ConfigExprRef is the
+// generic reference expression used for all of those, so we have to look
+// at the underlying address's kind to tell them apart. Repetition
+// expressions are drawn from parentNS.GenerateExpression the same as
+// arguments are, so they can resolve to a sibling module's output just as
+// easily and need the same check.
+func moduleCallHasCrossModuleRef(mc *stressgen.ConfigModuleCall) bool {
+	for _, expr := range mc.Arguments {
+		if exprRefersToModuleCall(expr) {
+			return true
+		}
+	}
+	for _, expr := range mc.DependsOn {
+		if exprRefersToModuleCall(expr) {
+			return true
+		}
+	}
+	if mc.ForEachExpr != nil {
+		for _, expr := range mc.ForEachExpr.Exprs {
+			if exprRefersToModuleCall(expr) {
+				return true
+			}
+		}
+	}
+	if mc.CountExpr != nil && exprRefersToModuleCall(mc.CountExpr.Expr) {
+		return true
+	}
+	return false
+}
+
+func exprRefersToModuleCall(expr stressgen.ConfigExpr) bool {
+	ref, ok := expr.(*stressgen.ConfigExprRef)
+	if !ok {
+		return false
+	}
+	_, ok = ref.Addr.(addrs.ModuleCallInstance)
+	return ok
+}
+
+// dedupChars returns the distinct characters of s in sorted order, so that
+// the same set of modes always yields the same string.
+func dedupChars(s string) string {
+	seen := make(map[rune]bool, len(s))
+	var out []rune
+	for _, r := range s {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return string(out)
+}
+
+// resourceTypeMix returns the sorted, deduplicated set of resource types
+// present anywhere in state.
+func resourceTypeMix(state *states.State) []string {
+	if state == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, ms := range state.Modules {
+		for _, res := range ms.Resources {
+			seen[res.Addr.Resource.Type] = true
+		}
+	}
+	types := make([]string, 0, len(seen))
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -37,24 +37,100 @@ func GenerateConfigModuleCall(rnd *rand.Rand, parentNS *Namespace) *ConfigModule
 	// on other objects in the configuration, even though our current model
 	// only allows for string dependencies directly.
 
+	profile := profileFor(parentNS)
+
+	// childNS needs to see the same profile as parentNS so that generation
+	// nested inside this module call -- including further levels of module
+	// nesting -- stays biased the way the caller asked for, rather than
+	// silently falling back to DefaultProfile.
+	childNS.Profile = profile
+
+	ret.ForEachExpr, ret.CountExpr = generateRepetition(rnd, parentNS, profile)
+
+	objCount := rnd.Intn(profile.MaxObjectsPerModule)
+	objs := make([]ConfigObject, 0, objCount+1) // +1 for the boilerplate object
+
+	// We always need a boilerplate object.
+	boilerplate := &ConfigBoilerplate{
+		ModuleAddr: childNS.ModuleAddr,
+		Providers: map[string]addrs.Provider{
+			"stressful": addrs.MustParseProviderSourceString("terraform.io/stresstest/stressful"),
+		},
+	}
+	objs = append(objs, boilerplate)
+
+	for i := 0; i < objCount; i++ {
+		obj := GenerateConfigObject(rnd, childNS)
+		objs = append(objs, obj)
+
+		if cv, ok := obj.(*ConfigVariable); ok {
+			// cv.CallerWillSet means the child module declared this
+			// variable without a default, so the caller must always set
+			// it. Otherwise it has a default, and profile.ArgumentSetProbability
+			// decides how often we nonetheless set it explicitly, to also
+			// exercise that path.
+			if cv.CallerWillSet || rnd.Float64() < profile.ArgumentSetProbability {
+				// The expression comes from parentNS here because the arguments
+				// are defined in the calling module, not the called module.
+				chosenExpr := parentNS.GenerateExpression(rnd)
+				ret.Arguments[cv.Addr] = chosenExpr
+			}
+		}
+	}
+
+	ret.Objects = objs
+	ret.DependsOn = generateDependsOn(rnd, parentNS, profile)
+	generateCrossModuleReferences(rnd, profile, objs)
+
+	declareConfigModuleCall(ret, childNS)
+	return ret
+}
+
+// generateRepetition randomly chooses, according to profile's
+// ModuleRepetitionWeights, whether a module call should be single-instance,
+// use "for_each", or use "count", and returns the corresponding expressions
+// (with exactly one, or neither, of the two return values set). It's
+// shared by GenerateConfigModuleCall and ConfigModuleCall.GenerateModified
+// so that a mutated call picks its new repetition mode the same way a
+// freshly-generated one would.
+func generateRepetition(rnd *rand.Rand, parentNS *Namespace, profile *GenerationProfile) (*ConfigExprForEach, *ConfigExprCount) {
+	// We support all three of the repetition modes for modules here: for_each
+	// over a map, count with a number, and single-instance mode. However,
+	// the rest of our generation strategy here works only with strings and
+	// so we need to do some trickery here to produce suitable inputs for
+	// the repetition arguments while still having them generate references
+	// sometimes, because the repetition arguments play an important role in
+	// constructing the dependency graph.
+	// We achieve this as follows:
+	// - for for_each, we generate a map with a random number of
+	//   randomly-generated keys where each of the values is an expression
+	//   randomly generated in our usual way.
+	// - for count, we generate a random expression in the usual way, assume
+	//   that the result will be convertable to a string (because that's our
+	//   current standard) and apply some predictable string functions to it
+	//   in order to deterministically derive a number.
+	// Both cases therefore allow for the meta-argument to potentially depend
+	// on other objects in the configuration, even though our current model
+	// only allows for string dependencies directly.
+
 	const (
 		chooseSingleInstance int = 0
 		chooseForEach        int = 1
 		chooseCount          int = 2
 	)
 	which := decideIndex(rnd, []int{
-		chooseSingleInstance: 4,
-		chooseForEach:        2,
-		chooseCount:          2,
+		chooseSingleInstance: profile.ModuleRepetitionWeights.SingleInstance,
+		chooseForEach:        profile.ModuleRepetitionWeights.ForEach,
+		chooseCount:          profile.ModuleRepetitionWeights.Count,
 	})
 	switch which {
 	case chooseSingleInstance:
-		// Nothing special to do, then. ForEachExpr and CountExpr will both
-		// be nil.
+		// Nothing special to do, then. Both return values stay nil.
+		return nil, nil
 	case chooseForEach:
 		// We need to generate some randomly-selected instance keys, and then
 		// associate each one with a randomly-selected expression.
-		n := rnd.Intn(9)
+		n := profile.ForEachKeyCountRange[0] + rnd.Intn(profile.ForEachKeyCountRange[1]-profile.ForEachKeyCountRange[0])
 		forEach := &ConfigExprForEach{
 			Exprs: make(map[string]ConfigExpr, n),
 		}
@@ -63,47 +139,170 @@ func GenerateConfigModuleCall(rnd *rand.Rand, parentNS *Namespace) *ConfigModule
 			expr := parentNS.GenerateExpression(rnd)
 			forEach.Exprs[k] = expr
 		}
-		ret.ForEachExpr = forEach
+		return forEach, nil
 	case chooseCount:
 		// We need to randomly select a source expression and then wrap it
 		// in our special ConfigExprCount type to make it appear as a
 		// randomly-chosen small integer instead of a string.
 		expr := parentNS.GenerateExpression(rnd)
-		ret.CountExpr = &ConfigExprCount{Expr: expr}
+		return nil, &ConfigExprCount{Expr: expr}
 	default:
 		// This suggests either a bug in decideIndex or in our call
 		// to decideIndex.
 		panic("invalid decision")
 	}
+}
 
-	objCount := rnd.Intn(25)
-	objs := make([]ConfigObject, 0, objCount+1) // +1 for the boilerplate object
+// generateDependsOn randomly decides whether the object currently being
+// generated should get an explicit "depends_on" argument and, if so, picks
+// up to profile.MaxDependsOnRefs references visible in parentNS for it.
+// This exercises Terraform's ordering graph independently of whatever
+// data-flow dependencies the object's other arguments and repetition
+// expressions already carry.
+//
+// It's intentionally agnostic to what kind of object is calling it -- it
+// only needs parentNS and profile -- so that it can be shared by every
+// ConfigObject generator that supports "depends_on", not just
+// GenerateConfigModuleCall. At the time of writing this package only has a
+// generator for module calls, so resources don't yet get an explicit
+// "depends_on" of their own, but a future resource generator should call
+// this the same way GenerateConfigModuleCall does below rather than
+// duplicating the logic.
+func generateDependsOn(rnd *rand.Rand, parentNS *Namespace, profile *GenerationProfile) []ConfigExpr {
+	if profile.MaxDependsOnRefs <= 0 || rnd.Float64() >= profile.DependsOnProbability {
+		return nil
+	}
 
-	// We always need a boilerplate object.
-	boilerplate := &ConfigBoilerplate{
-		ModuleAddr: childNS.ModuleAddr,
-		Providers: map[string]addrs.Provider{
-			"stressful": addrs.MustParseProviderSourceString("terraform.io/stresstest/stressful"),
-		},
+	n := 1 + rnd.Intn(profile.MaxDependsOnRefs)
+	seen := make(map[ConfigExpr]bool, n)
+	var refs []ConfigExpr
+	for i := 0; i < n; i++ {
+		// GenerateExpression already knows how to draw on parentNS's
+		// referenceables, so we sample it and keep only the direct
+		// references it happens to produce -- "depends_on" wants a bare
+		// reference, not an arbitrary computed expression.
+		expr := parentNS.GenerateExpression(rnd)
+		ref, ok := expr.(*ConfigExprRef)
+		if !ok || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
 	}
-	objs = append(objs, boilerplate)
+	return refs
+}
 
-	for i := 0; i < objCount; i++ {
-		obj := GenerateConfigObject(rnd, childNS)
-		objs = append(objs, obj)
+// generateCrossModuleReferences randomly rewires one sibling module call's
+// existing argument to instead reference another sibling's output,
+// independently of the value that argument would otherwise have been
+// given. This exercises Terraform's dependency solver across module
+// boundaries in a way that plain Arguments data flow, which only ever
+// flows from parentNS into a single call, cannot reach.
+func generateCrossModuleReferences(rnd *rand.Rand, profile *GenerationProfile, objs []ConfigObject) {
+	if rnd.Float64() >= profile.CrossModuleReferenceProbability {
+		return
+	}
 
-		if cv, ok := obj.(*ConfigVariable); ok && cv.CallerWillSet {
-			// The expression comes from parentNS here because the arguments
-			// are defined in the calling module, not the called module.
-			chosenExpr := parentNS.GenerateExpression(rnd)
-			ret.Arguments[cv.Addr] = chosenExpr
+	var calls []*ConfigModuleCall
+	for _, obj := range objs {
+		if mc, ok := obj.(*ConfigModuleCall); ok && len(mc.Arguments) > 0 {
+			calls = append(calls, mc)
 		}
 	}
+	if len(calls) < 2 {
+		return
+	}
 
-	ret.Objects = objs
+	srcIdx := rnd.Intn(len(calls))
+	dstIdx := rnd.Intn(len(calls))
+	if srcIdx == dstIdx {
+		return
+	}
+	src, dst := calls[srcIdx], calls[dstIdx]
 
-	declareConfigModuleCall(ret, childNS)
-	return ret
+	// dst is about to gain a reference to src's output, i.e. an edge
+	// dst -> src. If src already depends on dst, directly or transitively
+	// through some other sibling call, then adding that edge would close a
+	// cycle, which Terraform Core would reject as a configuration error
+	// rather than anything interesting about its dependency solver. We'd
+	// rather skip this round of generation than hand the solver a config
+	// that can never plan.
+	if moduleCallDependsOn(src, dst.Addr, calls) {
+		return
+	}
+
+	// We only know how to build a reference to a single-instance call's
+	// output; "count" and "for_each" calls have instance keys we'd have
+	// to choose between, so we skip those rather than guess one that
+	// might not exist once the repetition expression is evaluated.
+	if src.ForEachExpr != nil || src.CountExpr != nil {
+		return
+	}
+	if len(src.ChildNamespace.OutputValues) == 0 {
+		return
+	}
+	outputNames := make([]string, 0, len(src.ChildNamespace.OutputValues))
+	for name := range src.ChildNamespace.OutputValues {
+		outputNames = append(outputNames, name)
+	}
+	outputName := outputNames[rnd.Intn(len(outputNames))]
+	ref := NewConfigExprRef(
+		addrs.ModuleCallInstance{Call: src.Addr, Key: addrs.NoKey},
+		cty.GetAttrPath(outputName),
+	)
+
+	dstAddrs := make([]addrs.InputVariable, 0, len(dst.Arguments))
+	for addr := range dst.Arguments {
+		dstAddrs = append(dstAddrs, addr)
+	}
+	dst.Arguments[dstAddrs[rnd.Intn(len(dstAddrs))]] = ref
+}
+
+// moduleCallDependsOn reports whether mc already references target's
+// output, directly or transitively through some other call in calls, via
+// either its Arguments or its DependsOn. calls is expected to be the same
+// sibling list generateCrossModuleReferences was given, since that's the
+// full set of module calls a reference from mc could possibly chain
+// through.
+func moduleCallDependsOn(mc *ConfigModuleCall, target addrs.ModuleCall, calls []*ConfigModuleCall) bool {
+	byName := make(map[string]*ConfigModuleCall, len(calls))
+	for _, c := range calls {
+		byName[c.Addr.Name] = c
+	}
+
+	visited := make(map[string]bool, len(calls))
+	var visit func(*ConfigModuleCall) bool
+	visit = func(c *ConfigModuleCall) bool {
+		if visited[c.Addr.Name] {
+			return false
+		}
+		visited[c.Addr.Name] = true
+
+		refs := make([]ConfigExpr, 0, len(c.Arguments)+len(c.DependsOn))
+		for _, expr := range c.Arguments {
+			refs = append(refs, expr)
+		}
+		refs = append(refs, c.DependsOn...)
+
+		for _, expr := range refs {
+			ref, ok := expr.(*ConfigExprRef)
+			if !ok {
+				continue
+			}
+			modRef, ok := ref.Addr.(addrs.ModuleCallInstance)
+			if !ok {
+				continue
+			}
+			if modRef.Call == target {
+				return true
+			}
+			if next, ok := byName[modRef.Call.Name]; ok && visit(next) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(mc)
 }
 
 // declareConfigModuleCall creates the declaration of the given module call in
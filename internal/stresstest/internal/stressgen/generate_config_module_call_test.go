@@ -0,0 +1,71 @@
+package stressgen
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/addrs"
+)
+
+func TestModuleCallDependsOn(t *testing.T) {
+	addrA := addrs.ModuleCall{Name: "a"}
+	addrB := addrs.ModuleCall{Name: "b"}
+	addrC := addrs.ModuleCall{Name: "c"}
+
+	refTo := func(addr addrs.ModuleCall) ConfigExpr {
+		return NewConfigExprRef(addrs.ModuleCallInstance{Call: addr, Key: addrs.NoKey}, cty.GetAttrPath("out"))
+	}
+
+	// a -> b -> c, so a transitively depends on c even though it never
+	// references c directly.
+	a := &ConfigModuleCall{
+		Addr:      addrA,
+		Arguments: map[addrs.InputVariable]ConfigExpr{{Name: "in"}: refTo(addrB)},
+	}
+	b := &ConfigModuleCall{
+		Addr:      addrB,
+		Arguments: map[addrs.InputVariable]ConfigExpr{{Name: "in"}: refTo(addrC)},
+	}
+	c := &ConfigModuleCall{
+		Addr:      addrC,
+		Arguments: map[addrs.InputVariable]ConfigExpr{},
+	}
+	calls := []*ConfigModuleCall{a, b, c}
+
+	if !moduleCallDependsOn(a, addrB, calls) {
+		t.Error("expected a to directly depend on b")
+	}
+	if !moduleCallDependsOn(a, addrC, calls) {
+		t.Error("expected a to transitively depend on c through b")
+	}
+	if moduleCallDependsOn(c, addrA, calls) {
+		t.Error("expected c not to depend on a")
+	}
+	if moduleCallDependsOn(b, addrA, calls) {
+		t.Error("expected b not to depend on a")
+	}
+	if moduleCallDependsOn(a, addrA, calls) {
+		t.Error("expected a not to depend on itself")
+	}
+}
+
+func TestModuleCallDependsOnViaDependsOn(t *testing.T) {
+	addrA := addrs.ModuleCall{Name: "a"}
+	addrB := addrs.ModuleCall{Name: "b"}
+
+	// An explicit depends_on entry should count the same as a data-flow
+	// reference through Arguments.
+	a := &ConfigModuleCall{
+		Addr: addrA,
+		DependsOn: []ConfigExpr{
+			NewConfigExprRef(addrs.ModuleCallInstance{Call: addrB, Key: addrs.NoKey}, cty.GetAttrPath("out")),
+		},
+	}
+	b := &ConfigModuleCall{Addr: addrB}
+	calls := []*ConfigModuleCall{a, b}
+
+	if !moduleCallDependsOn(a, addrB, calls) {
+		t.Error("expected a's depends_on entry to count as a dependency on b")
+	}
+}
@@ -0,0 +1,215 @@
+package stressgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// GenerationProfile centralizes the probability weights and size bounds that
+// drive stressgen's random decisions, so that callers can bias generation
+// toward particular constructs without recompiling.
+//
+// ModuleRepetitionWeights, MaxObjectsPerModule, ForEachKeyCountRange and
+// ArgumentSetProbability replace decideIndex weightings, rnd.Intn upper
+// bounds and the argument-setting decision that were previously hardcoded
+// at their point of use; the remaining fields tune "depends_on" and
+// cross-module reference generation. Generators read the profile from the
+// Namespace they generate into (see profileFor), falling back to
+// DefaultProfile when it has none, and GenerateConfigModuleCall passes it
+// on to the child namespace of each module call.
+type GenerationProfile struct {
+	// ModuleRepetitionWeights controls the relative likelihood of a
+	// generated module call being single-instance, using "for_each", or
+	// using "count". See GenerateConfigModuleCall.
+	ModuleRepetitionWeights ModuleRepetitionWeights
+
+	// MaxObjectsPerModule bounds how many child objects
+	// GenerateConfigModuleCall will generate inside a module call, in
+	// addition to the mandatory boilerplate object.
+	MaxObjectsPerModule int
+
+	// ForEachKeyCountRange bounds the number of instance keys generated
+	// for a "for_each" module call. The first element is inclusive, the
+	// second is exclusive, matching the arguments to rnd.Intn.
+	ForEachKeyCountRange [2]int
+
+	// ArgumentSetProbability is the likelihood, in the range [0,1], that
+	// an optional input variable with a default value is nonetheless
+	// explicitly set by its caller. It's consulted when generating a module
+	// call's arguments, both in GenerateConfigModuleCall and in
+	// ConfigModuleCall.GenerateModified.
+	ArgumentSetProbability float64
+
+	// DependsOnProbability is the likelihood, in the range [0,1], that a
+	// generated module call gets an explicit "depends_on" argument at all.
+	DependsOnProbability float64
+
+	// MaxDependsOnRefs bounds how many references a generated
+	// "depends_on" argument can contain.
+	MaxDependsOnRefs int
+
+	// CrossModuleReferenceProbability is the likelihood, in the range
+	// [0,1], that generating a module call's children also rewires one
+	// sibling module call's argument to reference another sibling's
+	// output, independently of the data flow the arguments would
+	// otherwise carry.
+	CrossModuleReferenceProbability float64
+}
+
+// ModuleRepetitionWeights holds the decideIndex weights used to choose
+// between the three ways a module call can be repeated (or not).
+type ModuleRepetitionWeights struct {
+	SingleInstance int
+	ForEach        int
+	Count          int
+}
+
+// DefaultProfile keeps the repetition weights, object count bound and
+// for_each key range stressgen used before GenerationProfile was
+// introduced: a mild preference for single-instance module calls, moderate
+// object counts, and small for_each maps. Unlike before, it also sets every
+// optional input variable explicitly and occasionally generates
+// "depends_on" and cross-module references.
+var DefaultProfile = &GenerationProfile{
+	ModuleRepetitionWeights: ModuleRepetitionWeights{
+		SingleInstance: 4,
+		ForEach:        2,
+		Count:          2,
+	},
+	MaxObjectsPerModule:             25,
+	ForEachKeyCountRange:            [2]int{0, 9},
+	ArgumentSetProbability:          1,
+	DependsOnProbability:            0.1,
+	MaxDependsOnRefs:                2,
+	CrossModuleReferenceProbability: 0.1,
+}
+
+// HeavyModulesProfile biases generation toward larger module bodies, so
+// that a generated configuration tends to have more objects -- and thus
+// more nested module calls -- at each level of the tree.
+var HeavyModulesProfile = &GenerationProfile{
+	ModuleRepetitionWeights: ModuleRepetitionWeights{
+		SingleInstance: 5,
+		ForEach:        2,
+		Count:          1,
+	},
+	MaxObjectsPerModule:             60,
+	ForEachKeyCountRange:            [2]int{0, 9},
+	ArgumentSetProbability:          1,
+	DependsOnProbability:            0.1,
+	MaxDependsOnRefs:                2,
+	CrossModuleReferenceProbability: 0.1,
+}
+
+// HeavyForEachProfile biases generation toward "for_each" module calls with
+// larger instance key sets, to explore deeply-nested for_each configurations
+// that are otherwise rare under DefaultProfile.
+var HeavyForEachProfile = &GenerationProfile{
+	ModuleRepetitionWeights: ModuleRepetitionWeights{
+		SingleInstance: 1,
+		ForEach:        6,
+		Count:          1,
+	},
+	MaxObjectsPerModule:             25,
+	ForEachKeyCountRange:            [2]int{1, 17},
+	ArgumentSetProbability:          1,
+	DependsOnProbability:            0.25,
+	MaxDependsOnRefs:                4,
+	CrossModuleReferenceProbability: 0.3,
+}
+
+// MinimalProfile produces the smallest, shallowest configurations stressgen
+// can generate, which is useful for fast smoke-testing of the generator
+// itself or of downstream tooling.
+var MinimalProfile = &GenerationProfile{
+	ModuleRepetitionWeights: ModuleRepetitionWeights{
+		SingleInstance: 1,
+		ForEach:        0,
+		Count:          0,
+	},
+	MaxObjectsPerModule:             3,
+	ForEachKeyCountRange:            [2]int{0, 1},
+	ArgumentSetProbability:          1,
+	DependsOnProbability:            0,
+	MaxDependsOnRefs:                0,
+	CrossModuleReferenceProbability: 0,
+}
+
+// LoadGenerationProfile reads a GenerationProfile from the file at the given
+// path, selecting a JSON or TOML decoder based on the file extension. The
+// decoded profile is validated before being returned, so a malformed file
+// fails here with a descriptive error rather than panicking deep inside
+// some later generation call.
+func LoadGenerationProfile(path string) (*GenerationProfile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading generation profile: %w", err)
+	}
+
+	profile := *DefaultProfile // start from the defaults so partial files are valid
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err := json.Unmarshal(raw, &profile); err != nil {
+			return nil, fmt.Errorf("parsing generation profile as JSON: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(raw, &profile); err != nil {
+			return nil, fmt.Errorf("parsing generation profile as TOML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized generation profile format %q; use .json or .toml", ext)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid generation profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// Validate checks that p's fields are internally consistent and safe to
+// drive generation with, returning an error describing the first problem
+// it finds. The checks on MaxObjectsPerModule, ForEachKeyCountRange and
+// ModuleRepetitionWeights guard rnd.Intn and decideIndex calls elsewhere in
+// the package that would otherwise panic partway through generating a
+// configuration instead of failing up front; the remaining checks keep
+// counts non-negative and probabilities within [0,1].
+func (p *GenerationProfile) Validate() error {
+	if p.MaxObjectsPerModule <= 0 {
+		return fmt.Errorf("MaxObjectsPerModule must be positive, got %d", p.MaxObjectsPerModule)
+	}
+	if lo, hi := p.ForEachKeyCountRange[0], p.ForEachKeyCountRange[1]; lo < 0 || hi <= lo {
+		return fmt.Errorf("ForEachKeyCountRange must satisfy 0 <= [0] < [1], got %v", p.ForEachKeyCountRange)
+	}
+	if w := p.ModuleRepetitionWeights; w.SingleInstance < 0 || w.ForEach < 0 || w.Count < 0 {
+		return fmt.Errorf("ModuleRepetitionWeights must not contain negative weights, got %+v", w)
+	}
+	if w := p.ModuleRepetitionWeights; w.SingleInstance+w.ForEach+w.Count <= 0 {
+		return fmt.Errorf("ModuleRepetitionWeights must have at least one positive weight, got %+v", w)
+	}
+	if p.MaxDependsOnRefs < 0 {
+		return fmt.Errorf("MaxDependsOnRefs must not be negative, got %d", p.MaxDependsOnRefs)
+	}
+	if v := p.ArgumentSetProbability; v < 0 || v > 1 {
+		return fmt.Errorf("ArgumentSetProbability must be in the range [0,1], got %g", v)
+	}
+	if v := p.DependsOnProbability; v < 0 || v > 1 {
+		return fmt.Errorf("DependsOnProbability must be in the range [0,1], got %g", v)
+	}
+	if v := p.CrossModuleReferenceProbability; v < 0 || v > 1 {
+		return fmt.Errorf("CrossModuleReferenceProbability must be in the range [0,1], got %g", v)
+	}
+	return nil
+}
+
+// profileFor returns the GenerationProfile associated with ns, or
+// DefaultProfile if ns doesn't have one set.
+func profileFor(ns *Namespace) *GenerationProfile {
+	if ns != nil && ns.Profile != nil {
+		return ns.Profile
+	}
+	return DefaultProfile
+}
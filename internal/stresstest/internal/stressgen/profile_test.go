@@ -0,0 +1,84 @@
+package stressgen
+
+import "testing"
+
+func TestGenerationProfileValidate(t *testing.T) {
+	tests := map[string]struct {
+		mutate  func(*GenerationProfile)
+		wantErr bool
+	}{
+		"defaults are valid": {
+			mutate: func(p *GenerationProfile) {},
+		},
+		"non-positive MaxObjectsPerModule": {
+			mutate:  func(p *GenerationProfile) { p.MaxObjectsPerModule = 0 },
+			wantErr: true,
+		},
+		"empty ForEachKeyCountRange": {
+			mutate:  func(p *GenerationProfile) { p.ForEachKeyCountRange = [2]int{5, 5} },
+			wantErr: true,
+		},
+		"reversed ForEachKeyCountRange": {
+			mutate:  func(p *GenerationProfile) { p.ForEachKeyCountRange = [2]int{5, 1} },
+			wantErr: true,
+		},
+		"negative ForEachKeyCountRange lower bound": {
+			mutate:  func(p *GenerationProfile) { p.ForEachKeyCountRange = [2]int{-1, 5} },
+			wantErr: true,
+		},
+		"all-zero ModuleRepetitionWeights": {
+			mutate:  func(p *GenerationProfile) { p.ModuleRepetitionWeights = ModuleRepetitionWeights{} },
+			wantErr: true,
+		},
+		"negative ModuleRepetitionWeights": {
+			mutate:  func(p *GenerationProfile) { p.ModuleRepetitionWeights.Count = -1 },
+			wantErr: true,
+		},
+		"negative MaxDependsOnRefs": {
+			mutate:  func(p *GenerationProfile) { p.MaxDependsOnRefs = -1 },
+			wantErr: true,
+		},
+		"out-of-range ArgumentSetProbability": {
+			mutate:  func(p *GenerationProfile) { p.ArgumentSetProbability = 1.5 },
+			wantErr: true,
+		},
+		"out-of-range DependsOnProbability": {
+			mutate:  func(p *GenerationProfile) { p.DependsOnProbability = -0.1 },
+			wantErr: true,
+		},
+		"out-of-range CrossModuleReferenceProbability": {
+			mutate:  func(p *GenerationProfile) { p.CrossModuleReferenceProbability = 2 },
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			profile := *DefaultProfile
+			tt.mutate(&profile)
+
+			err := profile.Validate()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	for name, profile := range map[string]*GenerationProfile{
+		"DefaultProfile":      DefaultProfile,
+		"HeavyModulesProfile": HeavyModulesProfile,
+		"HeavyForEachProfile": HeavyForEachProfile,
+		"MinimalProfile":      MinimalProfile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := profile.Validate(); err != nil {
+				t.Fatalf("built-in profile failed validation: %s", err)
+			}
+		})
+	}
+}
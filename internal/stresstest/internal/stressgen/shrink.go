@@ -0,0 +1,404 @@
+package stressgen
+
+import (
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/addrs"
+)
+
+// Config represents the complete set of top-level ConfigObjects generated
+// for a stressgen run, along with the root Namespace they were declared
+// against. It's the unit that Shrink operates on: a candidate is just
+// another Config built from a subset or simplification of an existing one.
+type Config struct {
+	RootNS      *Namespace
+	RootObjects []ConfigObject
+}
+
+// Shrink takes a Config that's known to reproduce some failure -- as
+// determined by oracle, which returns true if the given Config still
+// reproduces the failure when instantiated and checked -- and returns a
+// smaller Config that still reproduces it.
+//
+// This is a delta-debugging search over the tree of ConfigObjects rooted
+// at cfg.RootObjects: at each step we try deleting a ConfigObject outright,
+// inlining a child module call into its parent, collapsing a "for_each"
+// down to a single instance key, converting "count" to single-instance, or
+// replacing an argument expression with a plain literal, and keep the
+// first such simplification that the oracle still accepts as failing.
+// Whenever a transformation removes a module call or narrows its "for_each"
+// keys, we also drop any sibling reference to the instance(s) that no
+// longer exist, so that an accepted candidate is always well-formed on its
+// own terms rather than merely one the oracle happened to reject for an
+// unrelated reason. We repeat until a full pass over the tree makes no
+// further progress, at which point the result is a local minimum.
+//
+// Shrink never mutates cfg. If oracle rejects cfg itself, Shrink returns
+// cfg unchanged; otherwise it returns a new Config, which may share any
+// ConfigObjects it left unchanged with cfg.
+func Shrink(cfg *Config, oracle func(*Config) bool) *Config {
+	if !oracle(cfg) {
+		// The starting point doesn't actually reproduce the failure, so
+		// there's nothing valid for us to shrink towards. We return what
+		// we were given rather than guessing.
+		return cfg
+	}
+
+	cur := cfg.RootObjects
+	for {
+		next, changed := shrinkObjects(cur, func(candidate []ConfigObject) bool {
+			return oracle(&Config{RootNS: cfg.RootNS, RootObjects: candidate})
+		})
+		if !changed {
+			return &Config{RootNS: cfg.RootNS, RootObjects: next}
+		}
+		cur = next
+	}
+}
+
+// shrinkObjects tries, in turn, to remove each object in objs, to inline
+// each child module call, and to simplify each module call in place,
+// accepting the first candidate that test still reports as reproducing
+// the failure.
+func shrinkObjects(objs []ConfigObject, test func([]ConfigObject) bool) ([]ConfigObject, bool) {
+	for i, obj := range objs {
+		if _, ok := obj.(*ConfigBoilerplate); ok {
+			// GenerateConfigModuleCall always places the mandatory
+			// boilerplate object at index 0 of a module call's Objects;
+			// removing it can never simplify a repro, only invalidate it.
+			continue
+		}
+		candidate := removeAt(objs, i)
+		if mc, ok := obj.(*ConfigModuleCall); ok {
+			candidate, _ = pruneReferencesMatching(candidate, matchesModule(mc.Addr))
+		}
+		if test(candidate) {
+			return candidate, true
+		}
+	}
+
+	for i, obj := range objs {
+		mc, ok := obj.(*ConfigModuleCall)
+		if !ok {
+			continue
+		}
+		if candidate, ok := tryInlineModuleCall(objs, i, mc, test); ok {
+			return candidate, true
+		}
+	}
+
+	for i, obj := range objs {
+		mc, ok := obj.(*ConfigModuleCall)
+		if !ok {
+			// We only know how to look inside ConfigModuleCall; other
+			// object kinds can only be removed or inlined, which we
+			// already tried above.
+			continue
+		}
+		if candidate, ok := tryCollapseForEach(objs, i, mc, test); ok {
+			return candidate, true
+		}
+
+		simplified, ok := shrinkModuleCall(mc, func(candidateMC *ConfigModuleCall) bool {
+			candidateObjs := append([]ConfigObject(nil), objs...)
+			candidateObjs[i] = candidateMC
+			return test(candidateObjs)
+		})
+		if ok {
+			out := append([]ConfigObject(nil), objs...)
+			out[i] = simplified
+			return out, true
+		}
+	}
+
+	return objs, false
+}
+
+// tryInlineModuleCall attempts to replace objs[i] (known to be mc) with
+// mc's own child objects spliced directly into objs, dropping the module
+// layer entirely. Any sibling reference to mc's outputs is dropped at the
+// same time, since inlining removes the module instance those outputs
+// belonged to.
+func tryInlineModuleCall(objs []ConfigObject, i int, mc *ConfigModuleCall, test func([]ConfigObject) bool) ([]ConfigObject, bool) {
+	if len(mc.Objects) == 0 {
+		return nil, false
+	}
+
+	// mc.Objects[0] is always its own mandatory boilerplate, which has no
+	// place in the parent module once inlined.
+	inlined := mc.Objects[1:]
+
+	candidate := make([]ConfigObject, 0, len(objs)-1+len(inlined))
+	candidate = append(candidate, objs[:i]...)
+	candidate = append(candidate, inlined...)
+	candidate = append(candidate, objs[i+1:]...)
+
+	candidate, _ = pruneReferencesMatching(candidate, matchesModule(mc.Addr))
+
+	if test(candidate) {
+		return candidate, true
+	}
+	return nil, false
+}
+
+// tryCollapseForEach attempts to narrow objs[i] (known to be mc) down to a
+// single "for_each" instance key, dropping any sibling reference to the
+// keys that are no longer declared.
+func tryCollapseForEach(objs []ConfigObject, i int, mc *ConfigModuleCall, test func([]ConfigObject) bool) ([]ConfigObject, bool) {
+	if mc.ForEachExpr == nil || len(mc.ForEachExpr.Exprs) <= 1 {
+		return nil, false
+	}
+
+	for k, expr := range mc.ForEachExpr.Exprs {
+		dropped := make(map[string]bool, len(mc.ForEachExpr.Exprs)-1)
+		for otherKey := range mc.ForEachExpr.Exprs {
+			if otherKey != k {
+				dropped[otherKey] = true
+			}
+		}
+
+		candidateMC := shallowCopyModuleCall(mc)
+		candidateMC.ForEachExpr = &ConfigExprForEach{
+			Exprs: map[string]ConfigExpr{k: expr},
+		}
+
+		candidate := append([]ConfigObject(nil), objs...)
+		candidate[i] = candidateMC
+		candidate, _ = pruneReferencesMatching(candidate, matchesModuleKeys(mc.Addr, dropped))
+
+		if test(candidate) {
+			return candidate, true
+		}
+		// One collapsed key is enough signal either way; trying every
+		// key individually isn't worth the extra oracle runs.
+		break
+	}
+	return nil, false
+}
+
+// shrinkModuleCall applies the module-call-specific simplifications that
+// can't create a dangling reference elsewhere in the tree -- dropping
+// "count" in favor of single-instance, shrinking child objects, and
+// flattening argument expressions to literals -- accepting the first one
+// that test reports as reproducing the failure.
+func shrinkModuleCall(mc *ConfigModuleCall, test func(*ConfigModuleCall) bool) (*ConfigModuleCall, bool) {
+	if mc.CountExpr != nil {
+		candidate := shallowCopyModuleCall(mc)
+		candidate.CountExpr = nil
+		if test(candidate) {
+			return candidate, true
+		}
+	}
+
+	if childObjs, changed := shrinkObjects(mc.Objects, func(candidateObjs []ConfigObject) bool {
+		candidate := shallowCopyModuleCall(mc)
+		candidate.Objects = candidateObjs
+		return test(candidate)
+	}); changed {
+		candidate := shallowCopyModuleCall(mc)
+		candidate.Objects = childObjs
+		return candidate, true
+	}
+
+	for addr, expr := range mc.Arguments {
+		if _, ok := expr.(*ConfigExprLiteral); ok {
+			continue // already as simple as it gets
+		}
+		candidate := shallowCopyModuleCall(mc)
+		candidate.Arguments = copyArguments(mc.Arguments)
+		candidate.Arguments[addr] = &ConfigExprLiteral{Value: cty.StringVal("shrunk")}
+		if test(candidate) {
+			return candidate, true
+		}
+	}
+
+	return mc, false
+}
+
+// matchesModule returns a predicate matching any reference into the given
+// module call, regardless of instance key -- the shape a reference takes
+// when the whole call has been removed or inlined.
+func matchesModule(addr addrs.ModuleCall) func(addrs.Referenceable) bool {
+	return func(r addrs.Referenceable) bool {
+		mci, ok := r.(addrs.ModuleCallInstance)
+		return ok && mci.Call == addr
+	}
+}
+
+// matchesModuleKeys returns a predicate matching a reference into the
+// given module call at one of the given instance keys specifically -- the
+// shape a reference takes when only some of a "for_each" call's keys have
+// been dropped.
+func matchesModuleKeys(addr addrs.ModuleCall, keys map[string]bool) func(addrs.Referenceable) bool {
+	return func(r addrs.Referenceable) bool {
+		mci, ok := r.(addrs.ModuleCallInstance)
+		if !ok || mci.Call != addr {
+			return false
+		}
+		sk, ok := mci.Key.(addrs.StringKey)
+		return ok && keys[string(sk)]
+	}
+}
+
+// refRewriter is implemented by ConfigObject kinds that can themselves
+// hold ConfigExprs referencing other objects, letting pruneReferencesMatching
+// rewrite or drop those references generically instead of hardcoding each
+// concrete object kind it knows how to look inside. ConfigModuleCall is the
+// only kind that implements it today; any future object kind that can hold
+// a reference (for example a resource with its own "depends_on") should
+// implement it too rather than this file growing another type-specific case.
+type refRewriter interface {
+	rewriteReferences(match func(addrs.Referenceable) bool) (ConfigObject, bool)
+}
+
+var _ refRewriter = (*ConfigModuleCall)(nil)
+
+// rewriteReferences implements refRewriter.
+func (mc *ConfigModuleCall) rewriteReferences(match func(addrs.Referenceable) bool) (ConfigObject, bool) {
+	return pruneModuleCallRefs(mc, match)
+}
+
+// pruneReferencesMatching walks objs, replacing or dropping any ConfigExpr
+// whose reference address satisfies match, so that a simplification which
+// removed or renamed a referenceable doesn't leave a dangling reference
+// behind it. It returns objs unchanged (the very same slice) when nothing
+// needed pruning, so callers can cheaply tell whether a copy was made.
+// Object kinds that don't implement refRewriter are left alone, since this
+// package has no way to know what references they might be holding.
+func pruneReferencesMatching(objs []ConfigObject, match func(addrs.Referenceable) bool) ([]ConfigObject, bool) {
+	changed := false
+	out := objs
+	for i, obj := range objs {
+		rewriter, ok := obj.(refRewriter)
+		if !ok {
+			continue
+		}
+		pruned, objChanged := rewriter.rewriteReferences(match)
+		if !objChanged {
+			continue
+		}
+		if !changed {
+			out = append([]ConfigObject(nil), objs...)
+			changed = true
+		}
+		out[i] = pruned
+	}
+	return out, changed
+}
+
+// pruneModuleCallRefs applies pruneReferencesMatching's rule to a single
+// module call's own Arguments, DependsOn, ForEachExpr, CountExpr, and
+// (recursively) its children.
+func pruneModuleCallRefs(mc *ConfigModuleCall, match func(addrs.Referenceable) bool) (*ConfigModuleCall, bool) {
+	var result *ConfigModuleCall
+	ensure := func() *ConfigModuleCall {
+		if result == nil {
+			c := shallowCopyModuleCall(mc)
+			c.Arguments = copyArguments(mc.Arguments)
+			result = c
+		}
+		return result
+	}
+
+	for addr, expr := range mc.Arguments {
+		if refMatches(expr, match) {
+			delete(ensure().Arguments, addr)
+		}
+	}
+
+	if len(mc.DependsOn) > 0 {
+		var kept []ConfigExpr
+		dropped := false
+		for _, expr := range mc.DependsOn {
+			if refMatches(expr, match) {
+				dropped = true
+				continue
+			}
+			kept = append(kept, expr)
+		}
+		if dropped {
+			ensure().DependsOn = kept
+		}
+	}
+
+	if mc.ForEachExpr != nil {
+		var newExprs map[string]ConfigExpr
+		for k, expr := range mc.ForEachExpr.Exprs {
+			if !refMatches(expr, match) {
+				continue
+			}
+			if newExprs == nil {
+				newExprs = make(map[string]ConfigExpr, len(mc.ForEachExpr.Exprs))
+				for k2, v2 := range mc.ForEachExpr.Exprs {
+					newExprs[k2] = v2
+				}
+			}
+			newExprs[k] = &ConfigExprLiteral{Value: cty.StringVal("shrunk")}
+		}
+		if newExprs != nil {
+			ensure().ForEachExpr = &ConfigExprForEach{Exprs: newExprs}
+		}
+	}
+
+	if mc.CountExpr != nil && refMatches(mc.CountExpr.Expr, match) {
+		ensure().CountExpr = &ConfigExprCount{Expr: &ConfigExprLiteral{Value: cty.NumberIntVal(1)}}
+	}
+
+	if childObjs, changed := pruneReferencesMatching(mc.Objects, match); changed {
+		ensure().Objects = childObjs
+	}
+
+	if result == nil {
+		return mc, false
+	}
+	return result, true
+}
+
+func refMatches(expr ConfigExpr, match func(addrs.Referenceable) bool) bool {
+	ref, ok := expr.(*ConfigExprRef)
+	if !ok {
+		return false
+	}
+	return match(ref.Addr)
+}
+
+func shallowCopyModuleCall(mc *ConfigModuleCall) *ConfigModuleCall {
+	copied := *mc
+	return &copied
+}
+
+func copyArguments(m map[addrs.InputVariable]ConfigExpr) map[addrs.InputVariable]ConfigExpr {
+	out := make(map[addrs.InputVariable]ConfigExpr, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func removeAt(objs []ConfigObject, i int) []ConfigObject {
+	out := make([]ConfigObject, 0, len(objs)-1)
+	out = append(out, objs[:i]...)
+	out = append(out, objs[i+1:]...)
+	return out
+}
+
+// ConfigExprLiteral is a ConfigExpr that always evaluates to a fixed,
+// pre-chosen value. Shrink uses it to replace expressions that reference
+// other objects -- and so drag a dependency chain along with them into a
+// bug report -- with something self-contained.
+type ConfigExprLiteral struct {
+	Value cty.Value
+}
+
+var _ ConfigExpr = (*ConfigExprLiteral)(nil)
+
+// BuildExpr implements ConfigExpr.
+func (e *ConfigExprLiteral) BuildExpr() hclwrite.Tokens {
+	return hclwrite.TokensForValue(e.Value)
+}
+
+// ExpectedValue implements ConfigExpr.
+func (e *ConfigExprLiteral) ExpectedValue(reg *Registry) cty.Value {
+	return e.Value
+}
@@ -0,0 +1,93 @@
+package stressgen
+
+import (
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hashicorp/terraform/addrs"
+)
+
+func TestShrinkRemovesUnnecessaryObjects(t *testing.T) {
+	keep := &ConfigModuleCall{
+		Addr:      addrs.ModuleCall{Name: "keep"},
+		Arguments: map[addrs.InputVariable]ConfigExpr{},
+		Objects:   []ConfigObject{&ConfigBoilerplate{}},
+	}
+	extra := &ConfigModuleCall{
+		Addr:      addrs.ModuleCall{Name: "extra"},
+		Arguments: map[addrs.InputVariable]ConfigExpr{},
+		Objects:   []ConfigObject{&ConfigBoilerplate{}},
+	}
+	cfg := &Config{
+		RootObjects: []ConfigObject{&ConfigBoilerplate{}, keep, extra},
+	}
+
+	oracle := func(c *Config) bool {
+		for _, obj := range c.RootObjects {
+			if mc, ok := obj.(*ConfigModuleCall); ok && mc.Addr.Name == "keep" {
+				return true
+			}
+		}
+		return false
+	}
+
+	got := Shrink(cfg, oracle)
+
+	if len(got.RootObjects) != 2 {
+		t.Fatalf("expected shrink to remove the unnecessary module call, got %d objects", len(got.RootObjects))
+	}
+	for _, obj := range got.RootObjects {
+		if mc, ok := obj.(*ConfigModuleCall); ok && mc.Addr.Name == "extra" {
+			t.Fatal("expected 'extra' module call to be removed, but it survived shrinking")
+		}
+	}
+}
+
+func TestShrinkPrunesDanglingReferencesWhenRemovingAModuleCall(t *testing.T) {
+	depAddr := addrs.InputVariable{Name: "in"}
+	removed := &ConfigModuleCall{
+		Addr:      addrs.ModuleCall{Name: "removed"},
+		Arguments: map[addrs.InputVariable]ConfigExpr{},
+		Objects:   []ConfigObject{&ConfigBoilerplate{}},
+	}
+	ref := NewConfigExprRef(
+		addrs.ModuleCallInstance{Call: removed.Addr, Key: addrs.NoKey},
+		cty.GetAttrPath("out"),
+	)
+	dependent := &ConfigModuleCall{
+		Addr:      addrs.ModuleCall{Name: "dependent"},
+		Arguments: map[addrs.InputVariable]ConfigExpr{depAddr: ref},
+		Objects:   []ConfigObject{&ConfigBoilerplate{}},
+	}
+	cfg := &Config{
+		RootObjects: []ConfigObject{&ConfigBoilerplate{}, removed, dependent},
+	}
+
+	// The oracle only cares that "dependent" survives; it has no opinion
+	// on "removed", so Shrink is free to delete it -- and, in doing so,
+	// must also drop dependent's now-dangling reference instead of
+	// leaving it pointing at a module call that no longer exists.
+	oracle := func(c *Config) bool {
+		for _, obj := range c.RootObjects {
+			if mc, ok := obj.(*ConfigModuleCall); ok && mc.Addr.Name == "dependent" {
+				return true
+			}
+		}
+		return false
+	}
+
+	got := Shrink(cfg, oracle)
+
+	for _, obj := range got.RootObjects {
+		mc, ok := obj.(*ConfigModuleCall)
+		if !ok || mc.Addr.Name != "dependent" {
+			continue
+		}
+		if _, stillSet := mc.Arguments[depAddr]; stillSet {
+			t.Fatal("expected the dangling reference to 'removed' to be pruned from dependent's arguments")
+		}
+		return
+	}
+	t.Fatal("expected 'dependent' module call to survive shrinking")
+}